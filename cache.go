@@ -0,0 +1,104 @@
+package main
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CacheEntry is the full upstream envelope a Cache stores, so a replayed hit
+// can reproduce the original status and headers rather than assuming 200 OK.
+type CacheEntry struct {
+	Status     string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Cache stores previously fetched sub-request responses, keyed by URL. TTL is
+// decided by the caller (typically from upstream Cache-Control/Expires
+// headers via cacheTTL) and enforced by the implementation.
+type Cache interface {
+	Get(url string) (CacheEntry, bool)
+	Put(url string, entry CacheEntry, ttl time.Duration)
+}
+
+type lruEntry struct {
+	key       string
+	entry     CacheEntry
+	expiresAt time.Time
+}
+
+// LRUCache is an in-memory Cache bounded by both entry count and total body
+// bytes; whichever limit is hit first evicts the least recently used entry.
+type LRUCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int
+	curBytes   int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+// NewLRUCache creates an LRUCache holding at most maxEntries entries and
+// maxBytes total body bytes.
+func NewLRUCache(maxEntries, maxBytes int) *LRUCache {
+	return &LRUCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+func (c *LRUCache) Get(url string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[url]
+	if !ok {
+		return CacheEntry{}, false
+	}
+
+	lru := el.Value.(*lruEntry)
+	if time.Now().After(lru.expiresAt) {
+		c.removeElement(el)
+		return CacheEntry{}, false
+	}
+
+	c.ll.MoveToFront(el)
+	return lru.entry, true
+}
+
+func (c *LRUCache) Put(url string, entry CacheEntry, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[url]; ok {
+		c.removeElement(el)
+	}
+
+	el := c.ll.PushFront(&lruEntry{key: url, entry: entry, expiresAt: time.Now().Add(ttl)})
+	c.items[url] = el
+	c.curBytes += len(entry.Body)
+
+	for c.ll.Len() > c.maxEntries || c.curBytes > c.maxBytes {
+		oldest := c.ll.Back()
+		if oldest == nil {
+			break
+		}
+		c.removeElement(oldest)
+	}
+}
+
+func (c *LRUCache) removeElement(el *list.Element) {
+	lru := el.Value.(*lruEntry)
+	c.ll.Remove(el)
+	delete(c.items, lru.key)
+	c.curBytes -= len(lru.entry.Body)
+}
@@ -0,0 +1,10 @@
+//go:build !redis
+
+package main
+
+// newCache builds the default in-memory Cache. redisAddr is accepted so
+// callers don't need a build-tag-specific signature, but is ignored here;
+// build with -tags redis to back the cache with Redis instead.
+func newCache(redisAddr string) Cache {
+	return NewLRUCache(DefaultCacheMaxEntries, DefaultCacheMaxBytes)
+}
@@ -0,0 +1,12 @@
+//go:build redis
+
+package main
+
+// newCache builds a RedisCache when redisAddr is set, falling back to the
+// default in-memory Cache otherwise.
+func newCache(redisAddr string) Cache {
+	if redisAddr != "" {
+		return NewRedisCache(redisAddr)
+	}
+	return NewLRUCache(DefaultCacheMaxEntries, DefaultCacheMaxBytes)
+}
@@ -0,0 +1,48 @@
+//go:build redis
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisCache is a Cache backed by Redis, selected via the `redis` build tag
+// for deployments that want a cache shared across multiple instances. Entries
+// are JSON-encoded since Redis values are opaque bytes.
+type RedisCache struct {
+	client *redis.Client
+}
+
+// NewRedisCache connects to a Redis instance at addr.
+func NewRedisCache(addr string) *RedisCache {
+	return &RedisCache{client: redis.NewClient(&redis.Options{Addr: addr})}
+}
+
+func (c *RedisCache) Get(url string) (CacheEntry, bool) {
+	raw, err := c.client.Get(context.Background(), url).Bytes()
+	if err != nil {
+		return CacheEntry{}, false
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return CacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *RedisCache) Put(url string, entry CacheEntry, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	c.client.Set(context.Background(), url, raw, ttl)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRUCacheEvictsLeastRecentlyUsedByCount(t *testing.T) {
+	c := NewLRUCache(2, 1<<20)
+
+	c.Put("a", CacheEntry{Body: []byte("a")}, time.Minute)
+	c.Put("b", CacheEntry{Body: []byte("b")}, time.Minute)
+	c.Put("c", CacheEntry{Body: []byte("c")}, time.Minute)
+
+	_, hit := c.Get("a")
+	require.False(t, hit, "a should have been evicted to keep the cache at maxEntries")
+
+	_, hit = c.Get("b")
+	require.True(t, hit)
+	_, hit = c.Get("c")
+	require.True(t, hit)
+}
+
+func TestLRUCacheEvictsByByteSize(t *testing.T) {
+	c := NewLRUCache(10, 10)
+
+	c.Put("a", CacheEntry{Body: []byte("0123456789")}, time.Minute)
+	c.Put("b", CacheEntry{Body: []byte("x")}, time.Minute)
+
+	_, hit := c.Get("a")
+	require.False(t, hit, "a should have been evicted once b pushed curBytes over maxBytes")
+
+	_, hit = c.Get("b")
+	require.True(t, hit)
+}
+
+func TestLRUCacheRecentAccessSurvivesEviction(t *testing.T) {
+	c := NewLRUCache(2, 1<<20)
+
+	c.Put("a", CacheEntry{Body: []byte("a")}, time.Minute)
+	c.Put("b", CacheEntry{Body: []byte("b")}, time.Minute)
+
+	_, hit := c.Get("a") // touch a so it's now the most recently used
+	require.True(t, hit)
+
+	c.Put("c", CacheEntry{Body: []byte("c")}, time.Minute)
+
+	_, hit = c.Get("a")
+	require.True(t, hit, "a was recently accessed so b, not a, should be evicted")
+	_, hit = c.Get("b")
+	require.False(t, hit)
+}
+
+func TestLRUCacheExpiresEntriesByTTL(t *testing.T) {
+	c := NewLRUCache(10, 1<<20)
+
+	c.Put("a", CacheEntry{Body: []byte("a")}, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	_, hit := c.Get("a")
+	require.False(t, hit)
+}
+
+func TestLRUCachePutWithNonPositiveTTLIsNoop(t *testing.T) {
+	c := NewLRUCache(10, 1<<20)
+
+	c.Put("a", CacheEntry{Body: []byte("a")}, 0)
+
+	_, hit := c.Get("a")
+	require.False(t, hit)
+}
+
+func TestLRUCacheRoundTripsFullEnvelope(t *testing.T) {
+	c := NewLRUCache(10, 1<<20)
+
+	entry := CacheEntry{
+		Status:     "201 Created",
+		StatusCode: 201,
+		Header:     map[string][]string{"Content-Type": {"application/json"}},
+		Body:       []byte(`{"ok":true}`),
+	}
+	c.Put("a", entry, time.Minute)
+
+	got, hit := c.Get("a")
+	require.True(t, hit)
+	require.Equal(t, entry, got)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheTTL derives how long a response may be cached from its headers,
+// preferring Cache-Control max-age over Expires as RFC 7234 requires. It
+// returns 0 when the response must not be cached.
+func cacheTTL(header http.Header) time.Duration {
+	if cc := header.Get("Cache-Control"); cc != "" {
+		maxAge, hasMaxAge := time.Duration(0), false
+		for _, directive := range strings.Split(cc, ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "no-store" || directive == "no-cache" {
+				return 0
+			}
+			if rest, ok := strings.CutPrefix(directive, "max-age="); ok && !hasMaxAge {
+				hasMaxAge = true
+				if secs, err := strconv.Atoi(rest); err == nil && secs > 0 {
+					maxAge = time.Duration(secs) * time.Second
+				}
+			}
+		}
+		if hasMaxAge {
+			return maxAge
+		}
+	}
+
+	if exp := header.Get("Expires"); exp != "" {
+		t, err := http.ParseTime(exp)
+		if err != nil {
+			return 0
+		}
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+		return 0
+	}
+
+	return 0
+}
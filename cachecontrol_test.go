@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheTTL(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{
+			name:   "max-age",
+			header: http.Header{"Cache-Control": {"max-age=60"}},
+			want:   60 * time.Second,
+		},
+		{
+			name:   "no-store wins over a later max-age",
+			header: http.Header{"Cache-Control": {"no-store, max-age=60"}},
+			want:   0,
+		},
+		{
+			name:   "no-cache",
+			header: http.Header{"Cache-Control": {"no-cache"}},
+			want:   0,
+		},
+		{
+			name:   "max-age zero is not cacheable",
+			header: http.Header{"Cache-Control": {"max-age=0"}},
+			want:   0,
+		},
+		{
+			name:   "malformed max-age falls back to not cacheable",
+			header: http.Header{"Cache-Control": {"max-age=soon"}},
+			want:   0,
+		},
+		{
+			name:   "no Cache-Control, no Expires",
+			header: http.Header{},
+			want:   0,
+		},
+		{
+			name:   "malformed Expires is not cacheable",
+			header: http.Header{"Expires": {"not-a-date"}},
+			want:   0,
+		},
+		{
+			name:   "Expires in the past is not cacheable",
+			header: http.Header{"Expires": {time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)}},
+			want:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, cacheTTL(tt.header))
+		})
+	}
+}
+
+func TestCacheTTLFromExpires(t *testing.T) {
+	header := http.Header{"Expires": {time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)}}
+
+	ttl := cacheTTL(header)
+	require.Greater(t, ttl, 59*time.Minute)
+	require.LessOrEqual(t, ttl, time.Hour)
+}
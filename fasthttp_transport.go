@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// fasthttpBufPool holds the pooled buffers sub-request bodies are copied
+// into, capped at maxBodyBytes per fetch.
+var fasthttpBufPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// FastHTTPTransport performs upstream GETs using fasthttp, reusing pooled
+// Request/Response objects to avoid the per-call allocations net/http incurs.
+type FastHTTPTransport struct {
+	client *fasthttp.Client
+}
+
+// NewFastHTTPTransport creates a FastHTTPTransport with the given per-request
+// timeout. StreamResponseBody is enabled so the body cap in Get is enforced
+// while reading, instead of after fasthttp has already buffered the whole
+// response in memory.
+func NewFastHTTPTransport(timeout time.Duration) *FastHTTPTransport {
+	return &FastHTTPTransport{
+		client: &fasthttp.Client{
+			ReadTimeout:        timeout,
+			WriteTimeout:       timeout,
+			StreamResponseBody: true,
+		},
+	}
+}
+
+// fasthttpResult carries the outcome of a background fasthttp.Client.Do call
+// back to Get, so Get can also select on ctx.Done().
+type fasthttpResult struct {
+	fetched *fetchedResponse
+	err     error
+}
+
+func (t *FastHTTPTransport) Get(ctx context.Context, url string, maxBodyBytes int64) (*fetchedResponse, error) {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+
+	req.SetRequestURI(url)
+	req.Header.SetMethod(fasthttp.MethodGet)
+
+	// DoDeadline only honors a fixed deadline, not ctx.Done(), so run it in
+	// the background and race it against ctx here. The goroutine (not this
+	// call) owns releasing req/resp, since on a ctx cancellation the fasthttp
+	// call may still be using them.
+	doneCh := make(chan fasthttpResult, 1)
+	go func() {
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+
+		var err error
+		if deadline, ok := ctx.Deadline(); ok {
+			err = t.client.DoDeadline(req, resp, deadline)
+		} else {
+			err = t.client.Do(req, resp)
+		}
+		if err != nil {
+			doneCh <- fasthttpResult{err: err}
+			return
+		}
+		fetched, err := fasthttpFetchedResponse(resp, maxBodyBytes)
+		doneCh <- fasthttpResult{fetched: fetched, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-doneCh:
+		return r.fetched, r.err
+	}
+}
+
+// fasthttpFetchedResponse copies resp's status/headers/body into a
+// fetchedResponse. The body is read from resp.BodyStream() through a
+// LimitReader, so the maxBodyBytes cap bounds the read itself rather than
+// truncating a body fasthttp already buffered in full.
+func fasthttpFetchedResponse(resp *fasthttp.Response, maxBodyBytes int64) (*fetchedResponse, error) {
+	buf := fasthttpBufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer fasthttpBufPool.Put(buf)
+
+	if _, err := buf.ReadFrom(io.LimitReader(resp.BodyStream(), maxBodyBytes+1)); err != nil {
+		return nil, err
+	}
+
+	body := buf.Bytes()
+	truncated := false
+	if int64(len(body)) > maxBodyBytes {
+		body = body[:maxBodyBytes]
+		truncated = true
+	}
+
+	header := make(http.Header)
+	resp.Header.VisitAll(func(k, v []byte) {
+		header.Add(string(k), string(v))
+	})
+
+	out := make([]byte, len(body))
+	copy(out, body)
+
+	return &fetchedResponse{
+		status:     fmt.Sprintf("%d %s", resp.StatusCode(), http.StatusText(resp.StatusCode())),
+		statusCode: resp.StatusCode(),
+		header:     header,
+		body:       out,
+		truncated:  truncated,
+	}, nil
+}
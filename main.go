@@ -1,93 +1,52 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
-	"io"
-	"io/ioutil"
+	"flag"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"sync"
 	"syscall"
 	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 const (
 	MaxConnections           = 100
 	MaxEmbeddedUrls          = 20
+	MaxURLLength             = 2048
 	MaxConcurrentSubRequests = 4
-)
-
-func multiplexer(w http.ResponseWriter, req *http.Request) {
-
-	if req.Method != http.MethodPost {
-		http.Error(w, "405 only POST method allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	urls, err := readEmbeddedStrings(req.Body, MaxEmbeddedUrls)
-	if err != nil {
-		http.Error(w, "500 getting urls from req", http.StatusInternalServerError)
-		return
-	}
-
-	client := &http.Client{
-		Timeout: time.Second,
-	}
-
-	ctx, cancel := context.WithCancel(context.Background())
-	go func() {
-		<-req.Context().Done()
-		cancel()
-	}()
-
-	ch := make(chan httpResult, 0)
-	pool := make(chan struct{}, MaxConcurrentSubRequests)
-	wg := &sync.WaitGroup{}
 
-	res := make(map[string]string, 0)
-	for _, url := range urls {
-		// In production could use redis or memcached to check already requested urls
-		wg.Add(1)
-		go getSubRequest(url, client, pool, wg, ch, ctx)
-	}
-
-	go func() {
-		wg.Wait()
-		close(ch)
-	}()
+	DefaultCacheMaxEntries = 1000
+	DefaultCacheMaxBytes   = 64 * 1024 * 1024
+)
 
-	for result := range ch {
+var (
+	transportFlag = flag.String("transport", "net-http", `sub-request transport: "net-http" or "fasthttp"`)
+	redisAddrFlag = flag.String("redis-addr", "", "Redis address for the cache backend; only takes effect when built with -tags redis")
 
-		if result.err != nil {
-			cancel()
-			http.Error(w, result.err.Error(), http.StatusInternalServerError)
-			return
-		}
+	readHeaderTimeoutFlag = flag.Duration("read-header-timeout", 5*time.Second, "max time to read request headers")
+	readTimeoutFlag       = flag.Duration("read-timeout", 10*time.Second, "max time to read the entire request")
+	writeTimeoutFlag      = flag.Duration("write-timeout", 30*time.Second, "max time to write the response")
+	idleTimeoutFlag       = flag.Duration("idle-timeout", 60*time.Second, "max time to wait for the next request on a keep-alive connection")
 
-		d, err := ioutil.ReadAll(result.resp.Body)
-		if err != nil {
-			http.Error(w, "500 subUrl req body reading error", http.StatusInternalServerError)
-			return
-		}
-		result.resp.Body.Close()
-
-		res[result.url] = string(d)
-	}
+	subRequestTimeoutFlag = flag.Duration("sub-request-timeout", time.Second, "per-URL upstream request budget")
+	batchTimeoutFlag      = flag.Duration("batch-timeout", 5*time.Second, "overall budget for a batch request")
+)
 
-	resBytes, err := json.Marshal(res)
-	if err != nil {
-		http.Error(w, "500 json result marshaling err", http.StatusInternalServerError)
-		return
+// newTransport builds the Transport selected by --transport.
+func newTransport(name string, timeout time.Duration) Transport {
+	switch name {
+	case "fasthttp":
+		return NewFastHTTPTransport(timeout)
+	default:
+		return NewNetHTTPTransport(timeout)
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(resBytes)
 }
 
 func limitedClientsHandler(f http.HandlerFunc, max int) http.HandlerFunc {
@@ -102,12 +61,29 @@ func limitedClientsHandler(f http.HandlerFunc, max int) http.HandlerFunc {
 
 func main() {
 
+	flag.Parse()
+
 	ctx, cancel := context.WithCancel(context.Background())
 
+	transport := newTransport(*transportFlag, *subRequestTimeoutFlag)
+	cache := newCache(*redisAddrFlag)
+	sfGroup := &singleflight.Group{}
+	metrics := &Metrics{}
+
+	srv := NewServer(transport, cache, sfGroup, metrics, *batchTimeoutFlag, *subRequestTimeoutFlag)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", limitedClientsHandler(srv.ServeHTTP, MaxConnections))
+	mux.Handle("/metrics", metrics)
+
 	httpServer := &http.Server{
-		Addr:        ":8080",
-		Handler:     limitedClientsHandler(multiplexer, MaxConnections),
-		BaseContext: func(_ net.Listener) context.Context { return ctx },
+		Addr:              ":8080",
+		Handler:           mux,
+		BaseContext:       func(_ net.Listener) context.Context { return ctx },
+		ReadHeaderTimeout: *readHeaderTimeoutFlag,
+		ReadTimeout:       *readTimeoutFlag,
+		WriteTimeout:      *writeTimeoutFlag,
+		IdleTimeout:       *idleTimeoutFlag,
 	}
 
 	go func() {
@@ -128,100 +104,46 @@ func main() {
 	<-signalChan
 	log.Print("os.Interrupt - shutting down...\n")
 
+	srv.BeginShutdown()
+
+	gracefulCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+
 	go func() {
 		<-signalChan
-		log.Fatal("os.Kill - terminating...\n")
+		log.Print("second signal received - cutting the grace period short\n")
+		cancelShutdown()
 	}()
 
-	gracefulCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancelShutdown()
+	shutdownErr := httpServer.Shutdown(gracefulCtx)
+	srv.Drain(gracefulCtx)
+	cancel()
 
-	if err := httpServer.Shutdown(gracefulCtx); err != nil {
-		log.Printf("shutdown error: %v\n", err)
+	if shutdownErr != nil {
+		log.Printf("shutdown error: %v\n", shutdownErr)
 		os.Exit(1)
-	} else {
-		log.Printf("gracefully stopped\n")
 	}
 
-	cancel()
+	log.Printf("gracefully stopped\n")
 
 	os.Exit(0)
 }
 
-func readEmbeddedStrings(r io.Reader, max int) (res []string, err error) {
-
-	bufReader := bufio.NewReader(r)
-	var b byte
-	var inWord bool
-	var word []byte
-	for {
-		b, err = bufReader.ReadByte()
-		if err == io.EOF {
-			if len(word) != 0 {
-				err = errors.New("not completed word exists")
-				res = res[:0]
-			} else {
-				err = nil
-			}
-			return
-		} else if err != nil {
-			return
-		}
-
-		// https://www.w3schools.com/js/js_json_syntax.asp
-		// In JSON, string values must be written with double quotes
-		if b == '"' {
-			inWord = !inWord
-			if !inWord {
-				if len(res) >= max {
-					res = res[:0]
-					err = errors.New("request max urls count exceeded")
-					return
-				}
-				res = append(res, string(word))
-				word = word[:0]
-			}
-			continue
-		} else if inWord {
-			word = append(word, b)
-		}
-	}
-}
-
-type httpResult struct {
-	resp *http.Response
-	url  string
-	err  error
+// errorResponse is the JSON body returned for client-facing request errors.
+type errorResponse struct {
+	Error string `json:"error"`
 }
 
-func getSubRequest(lUrl string, client *http.Client, pool chan struct{}, wg *sync.WaitGroup, ch chan httpResult, ctx context.Context) {
-	pool <- struct{}{}
-	defer wg.Done()
-	defer func() { <-pool }()
-
-	select {
-	case <-ctx.Done():
-		return
-	default:
+// writeJSONError translates err into an HTTP response: a *ParseError means the
+// client sent something we can't use (400), anything else is our fault (500).
+func writeJSONError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var perr *ParseError
+	if errors.As(err, &perr) {
+		status = http.StatusBadRequest
 	}
 
-	var err error
-	var resp *http.Response
-	defer func() {
-		ch <- httpResult{
-			resp: resp,
-			url:  lUrl,
-			err:  err,
-		}
-	}()
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, lUrl, nil)
-	if err != nil {
-		log.Printf("creating subReq err = %s\n", err.Error())
-		return
-	}
-	resp, err = client.Do(req)
-	if err != nil {
-		log.Printf("subReq err = %s\n", err.Error())
-	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
 }
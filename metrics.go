@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// Metrics holds process-wide counters exposed at the /metrics endpoint.
+type Metrics struct {
+	cacheHits   int64
+	cacheMisses int64
+}
+
+func (m *Metrics) RecordCacheHit() {
+	atomic.AddInt64(&m.cacheHits, 1)
+}
+
+func (m *Metrics) RecordCacheMiss() {
+	atomic.AddInt64(&m.cacheMisses, 1)
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "cache_hits_total %d\n", atomic.LoadInt64(&m.cacheHits))
+	fmt.Fprintf(w, "cache_misses_total %d\n", atomic.LoadInt64(&m.cacheMisses))
+}
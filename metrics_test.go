@@ -0,0 +1,23 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsServeHTTP(t *testing.T) {
+	m := &Metrics{}
+	m.RecordCacheHit()
+	m.RecordCacheHit()
+	m.RecordCacheMiss()
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+
+	require.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Body.String(), "cache_hits_total 2\n")
+	require.Contains(t, rec.Body.String(), "cache_misses_total 1\n")
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -31,12 +32,14 @@ func TestSimpleRequest(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, http.StatusOK, resp.StatusCode)
 
-	var res map[string]string
+	var res batchResponse
 
 	d, err := ioutil.ReadAll(resp.Body)
 	require.NoError(t, err)
 	require.NoError(t, json.Unmarshal(d, &res))
-	expRes := map[string]string{
+	require.Len(t, res.Results, 3)
+
+	expBodies := map[string]string{
 		"http://jsonplaceholder.typicode.com/posts/1": `{
   "userId": 1,
   "id": 1,
@@ -56,7 +59,16 @@ func TestSimpleRequest(t *testing.T) {
   "body": "et iusto sed quo iure\nvoluptatem occaecati omnis eligendi aut ad\nvoluptatem doloribus vel accusantium quis pariatur\nmolestiae porro eius odio et labore et velit aut"
 }`,
 	}
-	require.Equal(t, expRes, res)
+
+	for _, sub := range res.Results {
+		require.Empty(t, sub.Error)
+		require.Equal(t, http.StatusOK, sub.StatusCode)
+		require.False(t, sub.BodyTruncated)
+
+		body, err := base64.StdEncoding.DecodeString(sub.Body)
+		require.NoError(t, err)
+		require.Equal(t, expBodies[sub.URL], string(body))
+	}
 }
 
 func TestIncorrectRequest(t *testing.T) {
@@ -72,7 +84,13 @@ func TestIncorrectRequest(t *testing.T) {
 
 	resp, err := http.Post("http://localhost:8080/", "aplication/json", strings.NewReader(jsonUrls))
 	require.NoError(t, err)
-	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var errRes map[string]string
+	d, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(d, &errRes))
+	require.NotEmpty(t, errRes["error"])
 }
 
 func TestReadEmbeddedStrings(t *testing.T) {
@@ -86,24 +104,42 @@ func TestReadEmbeddedStrings(t *testing.T) {
 	}
 
 	{
-		r := strings.NewReader(`["url1", "url2", "ur]`)
-		res, err := readEmbeddedStrings(r, maxUrlsCount)
-		require.EqualError(t, err, "not completed word exists")
-		require.Len(t, res, 0)
+		r := strings.NewReader(`["http://a.com", "http://b.com", "ur]`)
+		_, err := readEmbeddedStrings(r, maxUrlsCount)
+		var perr *ParseError
+		require.ErrorAs(t, err, &perr)
+		require.Equal(t, ErrMalformedJSON, perr.Kind)
 	}
 
 	{
-		r := strings.NewReader(`["url1", "url2", "url3", "url4", "url5"]`)
-		res, err := readEmbeddedStrings(r, maxUrlsCount)
-		require.EqualError(t, err, "request max urls count exceeded")
-		require.Len(t, res, 0)
+		r := strings.NewReader(`["http://a.com", "http://b.com", "http://c.com", "http://d.com", "http://e.com"]`)
+		_, err := readEmbeddedStrings(r, maxUrlsCount)
+		var perr *ParseError
+		require.ErrorAs(t, err, &perr)
+		require.Equal(t, ErrTooManyURLs, perr.Kind)
 	}
 
 	{
-		r := strings.NewReader(`["url1", "url2", "url3", "url4"]`)
+		r := strings.NewReader(`["http://a.com", "http://b.com", "http://c.com", "http://d.com"]`)
 		res, err := readEmbeddedStrings(r, maxUrlsCount)
 		require.NoError(t, err)
-		require.Equal(t, []string{"url1", "url2", "url3", "url4"}, res)
+		require.Equal(t, []string{"http://a.com", "http://b.com", "http://c.com", "http://d.com"}, res)
+	}
+
+	{
+		r := strings.NewReader(`{"not": "an array"}`)
+		_, err := readEmbeddedStrings(r, maxUrlsCount)
+		var perr *ParseError
+		require.ErrorAs(t, err, &perr)
+		require.Equal(t, ErrWrongRootType, perr.Kind)
+	}
+
+	{
+		r := strings.NewReader(`["not-a-url"]`)
+		_, err := readEmbeddedStrings(r, maxUrlsCount)
+		var perr *ParseError
+		require.ErrorAs(t, err, &perr)
+		require.Equal(t, ErrInvalidURL, perr.Kind)
 	}
 
 }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// NetHTTPTransport performs upstream GETs using the standard net/http client.
+type NetHTTPTransport struct {
+	Client *http.Client
+}
+
+func NewNetHTTPTransport(timeout time.Duration) *NetHTTPTransport {
+	return &NetHTTPTransport{Client: &http.Client{Timeout: timeout}}
+}
+
+func (t *NetHTTPTransport) Get(ctx context.Context, lUrl string, maxBodyBytes int64) (*fetchedResponse, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, lUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.Client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+
+	fetched := &fetchedResponse{
+		status:     resp.Status,
+		statusCode: resp.StatusCode,
+		header:     resp.Header,
+	}
+
+	if int64(len(body)) > maxBodyBytes {
+		fetched.body = body[:maxBodyBytes]
+		fetched.truncated = true
+	} else {
+		fetched.body = body
+	}
+
+	return fetched, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ShutdownRetryAfterSeconds is advertised via Retry-After on the 503s a
+// Server returns once shutdown begins.
+const ShutdownRetryAfterSeconds = 5
+
+// Server owns the state shared across every "/" request: the sub-request
+// transport, cache and singleflight group, and a root context that is
+// cancelled the moment shutdown begins so in-flight sub-requests abort
+// promptly instead of riding out the grace period.
+type Server struct {
+	transport         Transport
+	cache             Cache
+	sfGroup           *singleflight.Group
+	metrics           *Metrics
+	batchTimeout      time.Duration
+	subRequestTimeout time.Duration
+
+	rootCtx    context.Context
+	cancelRoot context.CancelFunc
+
+	wg           sync.WaitGroup
+	shuttingDown int32 // atomic bool, 0 or 1
+}
+
+// NewServer builds a Server ready to accept requests. subRequestTimeout
+// bounds each individual upstream fetch shared via sfGroup, independent of
+// any one caller's own batchTimeout.
+func NewServer(transport Transport, cache Cache, sfGroup *singleflight.Group, metrics *Metrics, batchTimeout, subRequestTimeout time.Duration) *Server {
+	rootCtx, cancelRoot := context.WithCancel(context.Background())
+	return &Server{
+		transport:         transport,
+		cache:             cache,
+		sfGroup:           sfGroup,
+		metrics:           metrics,
+		batchTimeout:      batchTimeout,
+		subRequestTimeout: subRequestTimeout,
+		rootCtx:           rootCtx,
+		cancelRoot:        cancelRoot,
+	}
+}
+
+// BeginShutdown cancels the root context, so every in-flight sub-request
+// aborts promptly, and makes the server refuse new batches with 503.
+func (s *Server) BeginShutdown() {
+	atomic.StoreInt32(&s.shuttingDown, 1)
+	s.cancelRoot()
+}
+
+// Drain blocks until every in-flight batch finishes or ctx is done,
+// whichever happens first.
+func (s *Server) Drain(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+func (s *Server) isShuttingDown() bool {
+	return atomic.LoadInt32(&s.shuttingDown) == 1
+}
+
+// ServeHTTP is the "/" handler: it parses the batch, fans out sub-requests
+// and writes the combined envelope, refusing new work once shutdown begins.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+
+	if req.Method != http.MethodPost {
+		http.Error(w, "405 only POST method allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.isShuttingDown() {
+		w.Header().Set("Retry-After", strconv.Itoa(ShutdownRetryAfterSeconds))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(errorResponse{Error: "server is shutting down"})
+		return
+	}
+
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	req.Body = http.MaxBytesReader(w, req.Body, int64(MaxEmbeddedUrls*MaxURLLength))
+
+	urls, err := readEmbeddedStrings(req.Body, MaxEmbeddedUrls)
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), s.batchTimeout)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-s.rootCtx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ch := make(chan subResult, len(urls))
+	pool := make(chan struct{}, MaxConcurrentSubRequests)
+	wg := &sync.WaitGroup{}
+
+	for i, url := range urls {
+		wg.Add(1)
+		go getSubRequest(ctx, i, url, s.transport, pool, wg, ch, MaxResponseBytes, s.cache, s.sfGroup, s.metrics, s.rootCtx, s.subRequestTimeout)
+	}
+
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+
+	results := make([]SubResponse, len(urls))
+	for result := range ch {
+		results[result.index] = result.resp
+	}
+
+	resBytes, err := json.Marshal(batchResponse{Results: results})
+	if err != nil {
+		http.Error(w, "500 json result marshaling err", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resBytes)
+}
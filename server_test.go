@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/singleflight"
+)
+
+// TestServerDrainsInFlightOnShutdown starts a batch against a slow upstream,
+// triggers shutdown mid-flight, and asserts the client sees a clean response
+// (the in-flight sub-request reporting a cancellation, not a body) rather
+// than a hang, and that a batch started after shutdown begins is refused
+// with 503.
+func TestServerDrainsInFlightOnShutdown(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer slow.Close()
+
+	srv := NewServer(NewNetHTTPTransport(5*time.Second), NewLRUCache(10, 1<<20), &singleflight.Group{}, &Metrics{}, 2*time.Second, 5*time.Second)
+	ts := httptest.NewServer(http.HandlerFunc(srv.ServeHTTP))
+	defer ts.Close()
+
+	jsonUrls := fmt.Sprintf(`["%s"]`, slow.URL)
+
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		resp, err := http.Post(ts.URL, "application/json", strings.NewReader(jsonUrls))
+		resCh <- result{resp: resp, err: err}
+	}()
+
+	time.Sleep(50 * time.Millisecond) // let the batch reach the slow upstream
+	srv.BeginShutdown()
+
+	select {
+	case r := <-resCh:
+		require.NoError(t, r.err)
+		defer r.resp.Body.Close()
+		require.Equal(t, http.StatusOK, r.resp.StatusCode)
+
+		var res batchResponse
+		require.NoError(t, json.NewDecoder(r.resp.Body).Decode(&res))
+		require.Len(t, res.Results, 1)
+		require.NotEmpty(t, res.Results[0].Error)
+	case <-time.After(2 * time.Second):
+		t.Fatal("in-flight batch did not complete during shutdown")
+	}
+
+	resp2, err := http.Post(ts.URL, "application/json", strings.NewReader(jsonUrls))
+	require.NoError(t, err)
+	defer resp2.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, resp2.StatusCode)
+	require.NotEmpty(t, resp2.Header.Get("Retry-After"))
+}
+
+// TestServerEnforcesBatchTimeoutAlone asserts that --batch-timeout bounds a
+// batch on its own, without shutdown ever touching rootCtx: a batchTimeout
+// much shorter than both subRequestTimeout and the upstream's own latency
+// must still cut the batch off close to batchTimeout, not let it ride out
+// the slow upstream.
+func TestServerEnforcesBatchTimeoutAlone(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(2 * time.Second)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer slow.Close()
+
+	const batchTimeout = 100 * time.Millisecond
+	srv := NewServer(NewNetHTTPTransport(5*time.Second), NewLRUCache(10, 1<<20), &singleflight.Group{}, &Metrics{}, batchTimeout, 5*time.Second)
+	ts := httptest.NewServer(http.HandlerFunc(srv.ServeHTTP))
+	defer ts.Close()
+
+	jsonUrls := fmt.Sprintf(`["%s"]`, slow.URL)
+
+	start := time.Now()
+	resp, err := http.Post(ts.URL, "application/json", strings.NewReader(jsonUrls))
+	elapsed := time.Since(start)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Less(t, elapsed, time.Second, "batchTimeout should cut the batch off long before the 2s upstream responds")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var res batchResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	require.Len(t, res.Results, 1)
+	require.NotEmpty(t, res.Results[0].Error)
+}
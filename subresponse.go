@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// MaxResponseBytes bounds how much of each upstream body we buffer, so a
+// single huge response can't exhaust server memory.
+const MaxResponseBytes = 10 * 1024 * 1024 // 10MB
+
+// SubResponse is the per-URL outcome of a batch request. Body is the raw
+// upstream bytes, base64-encoded so arbitrary (including binary) payloads can
+// be carried safely in JSON. Error is set only for network/transport
+// failures and for a batch context that is cancelled or times out; a non-2xx
+// upstream response is still a successful SubResponse with Status/StatusCode
+// populated.
+type SubResponse struct {
+	URL           string      `json:"url"`
+	Status        string      `json:"status,omitempty"`
+	StatusCode    int         `json:"status_code,omitempty"`
+	Header        http.Header `json:"header,omitempty"`
+	Body          string      `json:"body,omitempty"`
+	BodyTruncated bool        `json:"body_truncated,omitempty"`
+	DurationMs    int64       `json:"duration_ms"`
+	Error         string      `json:"error,omitempty"`
+}
+
+// batchResponse is the top-level JSON body written back to the client.
+type batchResponse struct {
+	Results []SubResponse `json:"results"`
+}
+
+// subResult pairs a SubResponse with its position in the original request so
+// results can be reassembled in input order despite concurrent completion.
+type subResult struct {
+	index int
+	resp  SubResponse
+}
+
+// fetchedResponse is the outcome of an actual upstream round-trip, shared by
+// every caller that coalesced onto it via singleflight.
+type fetchedResponse struct {
+	status     string
+	statusCode int
+	header     http.Header
+	body       []byte
+	truncated  bool
+}
+
+// getSubRequest resolves lUrl, via cache or a (possibly shared, via sfGroup)
+// upstream fetch, and sends the outcome on ch. Only request construction and
+// transport errors, and ctx being done, are reported via SubResponse.Error; upstream non-2xx
+// statuses are reported as ordinary successful results. ctx is this caller's
+// own batch context: it bounds everything this call does, including waiting
+// on a shared fetch. rootCtx/subRequestTimeout bound the fetch itself, since
+// a fetch coalesced via sfGroup must keep running for any other caller still
+// waiting on it even after this caller's own ctx is done.
+func getSubRequest(ctx context.Context, index int, lUrl string, transport Transport, pool chan struct{}, wg *sync.WaitGroup, ch chan<- subResult, maxBodyBytes int64, cache Cache, sfGroup *singleflight.Group, metrics *Metrics, rootCtx context.Context, subRequestTimeout time.Duration) {
+	pool <- struct{}{}
+	defer wg.Done()
+	defer func() { <-pool }()
+
+	start := time.Now()
+	sub := SubResponse{URL: lUrl}
+
+	defer func() {
+		sub.DurationMs = time.Since(start).Milliseconds()
+		ch <- subResult{index: index, resp: sub}
+	}()
+
+	select {
+	case <-ctx.Done():
+		sub.Error = ctx.Err().Error()
+		return
+	default:
+	}
+
+	if entry, hit := cache.Get(lUrl); hit {
+		metrics.RecordCacheHit()
+		sub.Status = entry.Status
+		sub.StatusCode = entry.StatusCode
+		sub.Header = entry.Header
+		sub.Body = base64.StdEncoding.EncodeToString(entry.Body)
+		return
+	}
+	metrics.RecordCacheMiss()
+
+	// sfGroup.DoChan shares one upstream fetch across every caller currently
+	// asking for lUrl, so the fetch itself is bound to rootCtx plus its own
+	// timeout rather than this caller's ctx: it must keep running for any
+	// other coalesced caller even after this one gives up. But this caller's
+	// own wait for the result must still respect ctx, so select on both.
+	resultCh := sfGroup.DoChan(lUrl, func() (interface{}, error) {
+		fetchCtx, cancel := context.WithTimeout(rootCtx, subRequestTimeout)
+		defer cancel()
+		return transport.Get(fetchCtx, lUrl, maxBodyBytes)
+	})
+
+	var res singleflight.Result
+	select {
+	case <-ctx.Done():
+		sub.Error = ctx.Err().Error()
+		return
+	case res = <-resultCh:
+	}
+
+	if res.Err != nil {
+		sub.Error = res.Err.Error()
+		return
+	}
+
+	fetched := res.Val.(*fetchedResponse)
+	sub.Status = fetched.status
+	sub.StatusCode = fetched.statusCode
+	sub.Header = fetched.header
+	sub.Body = base64.StdEncoding.EncodeToString(fetched.body)
+	sub.BodyTruncated = fetched.truncated
+
+	// A truncated body is not cached: a cache hit could not report it as
+	// truncated.
+	if fetched.statusCode >= 200 && fetched.statusCode < 300 && !fetched.truncated {
+		if ttl := cacheTTL(fetched.header); ttl > 0 {
+			cache.Put(lUrl, CacheEntry{
+				Status:     fetched.status,
+				StatusCode: fetched.statusCode,
+				Header:     fetched.header,
+				Body:       fetched.body,
+			}, ttl)
+		}
+	}
+}
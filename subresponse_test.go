@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/singleflight"
+)
+
+// TestGetSubRequestCoalescesConcurrentFetches asserts that concurrent callers
+// asking for the same URL at the same time share a single upstream fetch via
+// sfGroup, rather than each issuing their own request.
+func TestGetSubRequestCoalescesConcurrentFetches(t *testing.T) {
+	var requests int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer ts.Close()
+
+	transport := NewNetHTTPTransport(5 * time.Second)
+	cache := NewLRUCache(10, 1<<20)
+	sfGroup := &singleflight.Group{}
+	metrics := &Metrics{}
+
+	const callers = 5
+	ch := make(chan subResult, callers)
+	pool := make(chan struct{}, callers)
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go getSubRequest(context.Background(), i, ts.URL, transport, pool, wg, ch, MaxResponseBytes, cache, sfGroup, metrics, context.Background(), 5*time.Second)
+	}
+	wg.Wait()
+	close(ch)
+
+	for result := range ch {
+		require.Empty(t, result.resp.Error)
+		require.Equal(t, http.StatusOK, result.resp.StatusCode)
+	}
+
+	require.EqualValues(t, 1, atomic.LoadInt64(&requests), "concurrent callers for the same URL should coalesce into one upstream fetch")
+}
+
+// TestGetSubRequestRespectsCallerCtxWhileCoalesced asserts that a caller whose
+// own ctx is cancelled well before a coalesced upstream fetch completes gets
+// its result promptly, instead of blocking for the full fetch duration.
+func TestGetSubRequestRespectsCallerCtxWhileCoalesced(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer ts.Close()
+
+	transport := NewNetHTTPTransport(5 * time.Second)
+	cache := NewLRUCache(10, 1<<20)
+	sfGroup := &singleflight.Group{}
+	metrics := &Metrics{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	ch := make(chan subResult, 1)
+	pool := make(chan struct{}, 1)
+	wg := &sync.WaitGroup{}
+
+	wg.Add(1)
+	start := time.Now()
+	go getSubRequest(ctx, 0, ts.URL, transport, pool, wg, ch, MaxResponseBytes, cache, sfGroup, metrics, context.Background(), 5*time.Second)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	result := <-ch
+	require.NotEmpty(t, result.resp.Error)
+	require.Less(t, elapsed, 200*time.Millisecond, "caller should return once its own ctx is done, not wait out the full upstream fetch")
+}
@@ -0,0 +1,10 @@
+package main
+
+import "context"
+
+// Transport performs a single upstream GET and returns its outcome. It
+// exists so the sub-request path can swap its underlying HTTP client (e.g.
+// net/http vs fasthttp) without touching the fetch/cache/singleflight logic.
+type Transport interface {
+	Get(ctx context.Context, url string, maxBodyBytes int64) (*fetchedResponse, error)
+}
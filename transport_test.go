@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestFastHTTPTransportAllocs mirrors fasthttp's own TestAllocationClient
+// pattern: run a TestSimpleRequest-shape GET against an in-process server and
+// compare allocs/op between the net/http and fasthttp transports.
+func TestFastHTTPTransportAllocs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"userId":1,"id":1,"title":"sunt aut facere","body":"quia et suscipit"}`))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	netHTTP := NewNetHTTPTransport(time.Second)
+	netAllocs := testing.AllocsPerRun(200, func() {
+		_, err := netHTTP.Get(ctx, ts.URL, MaxResponseBytes)
+		require.NoError(t, err)
+	})
+
+	fastHTTP := NewFastHTTPTransport(time.Second)
+	fastAllocs := testing.AllocsPerRun(200, func() {
+		_, err := fastHTTP.Get(ctx, ts.URL, MaxResponseBytes)
+		require.NoError(t, err)
+	})
+
+	t.Logf("allocs/op: net-http=%.1f fasthttp=%.1f", netAllocs, fastAllocs)
+	require.Less(t, fastAllocs, netAllocs)
+}
+
+// TestFastHTTPTransportRespectsContextCancellation asserts the fasthttp
+// transport aborts promptly when its ctx is cancelled, rather than riding
+// out the client's own (much longer) 10s timeout.
+func TestFastHTTPTransportRespectsContextCancellation(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(2 * time.Second)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer ts.Close()
+
+	fastHTTP := NewFastHTTPTransport(10 * time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := fastHTTP.Get(ctx, ts.URL, MaxResponseBytes)
+	require.Error(t, err)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+// TestFastHTTPTransportCapsResponseBody asserts the fasthttp transport
+// truncates at maxBodyBytes, matching NetHTTPTransport's behavior.
+func TestFastHTTPTransportCapsResponseBody(t *testing.T) {
+	const maxBodyBytes = 16
+	upstreamBody := bytes.Repeat([]byte("a"), maxBodyBytes*4)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(upstreamBody)
+	}))
+	defer ts.Close()
+
+	fastHTTP := NewFastHTTPTransport(time.Second)
+	fetched, err := fastHTTP.Get(context.Background(), ts.URL, maxBodyBytes)
+	require.NoError(t, err)
+	require.True(t, fetched.truncated)
+	require.Len(t, fetched.body, maxBodyBytes)
+}
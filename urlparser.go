@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// ParseErrorKind distinguishes the different ways a request body can fail to
+// yield a usable list of URLs, so callers can tell a client mistake (bad
+// JSON, bad URL) from something that should never happen.
+type ParseErrorKind string
+
+const (
+	ErrMalformedJSON ParseErrorKind = "malformed_json"
+	ErrTooManyURLs   ParseErrorKind = "too_many_urls"
+	ErrInvalidURL    ParseErrorKind = "invalid_url"
+	ErrWrongRootType ParseErrorKind = "wrong_root_type"
+)
+
+// ParseError is returned by readEmbeddedStrings for any input the client is
+// responsible for fixing; handlers should translate it into a 400 response.
+type ParseError struct {
+	Kind ParseErrorKind
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return e.Msg
+}
+
+// readEmbeddedStrings streams a JSON array of URL strings out of r, enforcing
+// max as it goes so we never buffer more of the body than necessary. Each
+// element is validated as an absolute http(s) URL.
+func readEmbeddedStrings(r io.Reader, max int) ([]string, error) {
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, &ParseError{Kind: ErrMalformedJSON, Msg: "malformed JSON: " + err.Error()}
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, &ParseError{Kind: ErrWrongRootType, Msg: "request body must be a JSON array of URLs"}
+	}
+
+	res := make([]string, 0, max)
+	for dec.More() {
+		if len(res) >= max {
+			return nil, &ParseError{Kind: ErrTooManyURLs, Msg: "request max urls count exceeded"}
+		}
+
+		var raw string
+		if err := dec.Decode(&raw); err != nil {
+			return nil, &ParseError{Kind: ErrMalformedJSON, Msg: "malformed JSON: " + err.Error()}
+		}
+
+		if err := validateURL(raw); err != nil {
+			return nil, err
+		}
+
+		res = append(res, raw)
+	}
+
+	if tok, err = dec.Token(); err != nil {
+		return nil, &ParseError{Kind: ErrMalformedJSON, Msg: "malformed JSON: " + err.Error()}
+	} else if delim, ok := tok.(json.Delim); !ok || delim != ']' {
+		return nil, &ParseError{Kind: ErrMalformedJSON, Msg: "malformed JSON: expected closing ]"}
+	}
+
+	return res, nil
+}
+
+// validateURL rejects anything that isn't an absolute http(s) URL.
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return &ParseError{Kind: ErrInvalidURL, Msg: fmt.Sprintf("invalid URL %q: %s", raw, err.Error())}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return &ParseError{Kind: ErrInvalidURL, Msg: fmt.Sprintf("invalid URL %q: scheme must be http or https", raw)}
+	}
+	return nil
+}